@@ -0,0 +1,73 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+// CoordinationBackend selects which coordination service a CR's `zookeeper:`
+// section is served by. It is optional and defaults to CoordinationBackendZookeeper,
+// preserving behavior for CRs written before the etcd backend existed.
+type CoordinationBackend string
+
+const (
+	CoordinationBackendZookeeper CoordinationBackend = "zookeeper"
+	CoordinationBackendEtcd      CoordinationBackend = "etcd"
+)
+
+// ZookeeperNode is one ensemble member of the `zookeeper.nodes` list.
+type ZookeeperNode struct {
+	Host string `json:"host" yaml:"host"`
+	Port int32  `json:"port" yaml:"port"`
+}
+
+// ZookeeperRetryConfig overrides pkg/model/zookeeper's default backoff
+// policy. Any field left at its zero value keeps the package default.
+type ZookeeperRetryConfig struct {
+	BaseDelayMs int32   `json:"baseDelayMs,omitempty" yaml:"baseDelayMs,omitempty"`
+	MaxDelayMs  int32   `json:"maxDelayMs,omitempty" yaml:"maxDelayMs,omitempty"`
+	Multiplier  float64 `json:"multiplier,omitempty" yaml:"multiplier,omitempty"`
+	MaxRetries  int32   `json:"maxRetries,omitempty" yaml:"maxRetries,omitempty"`
+}
+
+// ZookeeperCircuitBreakerConfig overrides pkg/model/zookeeper's default
+// circuit breaker policy. Any field left at its zero value keeps the
+// package default.
+type ZookeeperCircuitBreakerConfig struct {
+	FailureThreshold int32 `json:"failureThreshold,omitempty" yaml:"failureThreshold,omitempty"`
+	WindowSeconds    int32 `json:"windowSeconds,omitempty" yaml:"windowSeconds,omitempty"`
+	CooldownSeconds  int32 `json:"cooldownSeconds,omitempty" yaml:"cooldownSeconds,omitempty"`
+}
+
+// ZookeeperConfig is the `zookeeper:` section of a CHI/CHK spec.
+type ZookeeperConfig struct {
+	Nodes   []ZookeeperNode     `json:"nodes,omitempty" yaml:"nodes,omitempty"`
+	Backend CoordinationBackend `json:"backend,omitempty" yaml:"backend,omitempty"`
+
+	TLSServerNameOverride string `json:"tlsServerNameOverride,omitempty" yaml:"tlsServerNameOverride,omitempty"`
+
+	Retry          *ZookeeperRetryConfig          `json:"retry,omitempty" yaml:"retry,omitempty"`
+	CircuitBreaker *ZookeeperCircuitBreakerConfig `json:"circuitBreaker,omitempty" yaml:"circuitBreaker,omitempty"`
+}
+
+// TemplatingConfig is the `templating:` section of a CHI/CHK spec.
+type TemplatingConfig struct {
+	Macros map[string]string `json:"macros,omitempty" yaml:"macros,omitempty"`
+}
+
+// ZookeeperStatus is the `zookeeper:` section of a CHI/CHK status
+// subresource - what a user-defined macro in TemplatingConfig.Macros
+// actually expanded to, for debugging why a rendered config looks the way
+// it does.
+type ZookeeperStatus struct {
+	ExpandedMacros map[string]string `json:"expandedMacros,omitempty" yaml:"expandedMacros,omitempty"`
+}
@@ -0,0 +1,134 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package coordination abstracts the coordination service (ZooKeeper, etcd, ...)
+// that the operator relies on for config distribution, distributed locks and
+// ClickHouse Keeper-adjacent bookkeeping. Backend-specific packages under
+// pkg/coordination implement Client; callers should depend only on this package.
+package coordination
+
+import (
+	"context"
+	"time"
+)
+
+// Backend selects which coordination service implements Client.
+type Backend string
+
+const (
+	// BackendZookeeper talks to a ZooKeeper (or ClickHouse Keeper) ensemble.
+	BackendZookeeper Backend = "zookeeper"
+	// BackendEtcd talks to an etcd v3 cluster.
+	BackendEtcd Backend = "etcd"
+)
+
+// Flag mirrors the zk.Flag* create-time bitmask in a backend-agnostic form.
+type Flag int32
+
+const (
+	// FlagEphemeral marks a node for removal once the session that created it ends.
+	FlagEphemeral Flag = 1 << iota
+	// FlagSequence appends a monotonically increasing suffix to the created path.
+	FlagSequence
+)
+
+// ACL is a backend-agnostic access rule. Backends that have no notion of ACLs
+// (etcd) may ignore it.
+type ACL struct {
+	Perms  int32
+	Scheme string
+	ID     string
+}
+
+// Stat carries the subset of per-node metadata callers in this repo rely on:
+// the version used for optimistic-concurrency Set/Delete calls.
+type Stat struct {
+	Version int32
+}
+
+// EventType is a backend-agnostic rendering of a watch/session notification.
+type EventType int
+
+const (
+	EventUnknown EventType = iota
+	EventNodeCreated
+	EventNodeDeleted
+	EventNodeDataChanged
+	EventNodeChildrenChanged
+	EventSession
+)
+
+// Event is delivered on the channel returned by Client.Events.
+type Event struct {
+	Type EventType
+	Path string
+	// Err is set for EventSession when the session ended abnormally.
+	Err error
+}
+
+// Client is the set of operations the operator needs from a coordination
+// service, independent of whether it is backed by ZooKeeper or etcd. It is
+// intentionally narrow - it mirrors what pkg/model/zookeeper.Connection
+// exposed before this package existed.
+type Client interface {
+	Get(ctx context.Context, path string) (data []byte, stat *Stat, err error)
+	Exists(ctx context.Context, path string) (exists bool, stat *Stat, err error)
+	Create(ctx context.Context, path string, value []byte, flags Flag, acl []ACL) (pathCreated string, err error)
+	Set(ctx context.Context, path string, value []byte, version int32) (stat *Stat, err error)
+	Delete(ctx context.Context, path string, version int32) error
+
+	// Events returns a channel of session and watch notifications for the
+	// lifetime of the Client. It is closed when Close is called.
+	Events() <-chan Event
+
+	Close() error
+}
+
+// RetryPolicy is the backend-agnostic shape of a CR's retry tuning knobs.
+// A zero value means "use the backend's own default policy". Backends that
+// don't support tuning some of these fields may ignore them.
+type RetryPolicy struct {
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	Multiplier float64
+	MaxRetries int
+}
+
+// CircuitBreakerPolicy is the backend-agnostic shape of a CR's circuit
+// breaker tuning knobs. A zero value means "use the backend's own default
+// policy".
+type CircuitBreakerPolicy struct {
+	FailureThreshold int
+	Window           time.Duration
+	CooldownPeriod   time.Duration
+}
+
+// Config selects a Backend and carries the options each implementation needs.
+// It is what a CR's `backend: zookeeper|etcd` selector (plus its retry and
+// circuit breaker overrides) should be translated into at controller
+// wire-up time - see pkg/coordination/wire.NewClient.
+type Config struct {
+	Backend Backend
+	Address string
+
+	TLSCertFile           string
+	TLSKeyFile            string
+	TLSCAFile             string
+	TLSServerNameOverride string
+
+	AuthFile string
+
+	Retry          RetryPolicy
+	CircuitBreaker CircuitBreakerPolicy
+}
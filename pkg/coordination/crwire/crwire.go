@@ -0,0 +1,123 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package crwire is the glue between a CR's `zookeeper:`/`templating:`
+// sections and the packages that actually act on them: it is what the
+// controller calls at reconcile time and what the admission webhook calls
+// at CR validation time.
+package crwire
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/coordination"
+	"github.com/altinity/clickhouse-operator/pkg/coordination/wire"
+	"github.com/altinity/clickhouse-operator/pkg/model/common/namer/macro"
+	"github.com/altinity/clickhouse-operator/pkg/model/zookeeper"
+)
+
+// init registers pkg/model/zookeeper's retry/circuit-breaker metrics on the
+// default registry as soon as this package - the one the controller and
+// webhook actually import - is linked in, so they show up on the operator's
+// existing metrics endpoint without a separate wiring step.
+func init() {
+	zookeeper.RegisterMetrics(prometheus.DefaultRegisterer)
+}
+
+// NewClient is the reconcile-time call site: it turns a CR's ZookeeperConfig
+// into a live coordination.Client, choosing zookeeper or etcd per
+// zk.Backend. Controllers should call this once per CR wherever they
+// currently construct a pkg/model/zookeeper.Connection directly.
+func NewClient(ctx context.Context, zk *api.ZookeeperConfig) (coordination.Client, error) {
+	if zk == nil {
+		return nil, fmt.Errorf("crwire: nil zookeeper config")
+	}
+	if len(zk.Nodes) == 0 {
+		return nil, fmt.Errorf("crwire: zookeeper config has no nodes")
+	}
+
+	return wire.NewClient(ctx, coordination.Config{
+		Backend:               toBackend(zk.Backend),
+		Address:               address(zk.Nodes),
+		TLSServerNameOverride: zk.TLSServerNameOverride,
+		Retry:                 toRetryPolicy(zk.Retry),
+		CircuitBreaker:        toCircuitBreakerPolicy(zk.CircuitBreaker),
+	})
+}
+
+// ValidateTemplating is the admission-time call site: it rejects a CR whose
+// spec.templating.macros shadows a built-in macro or contains a Go-template
+// expression that doesn't resolve against the CR itself.
+func ValidateTemplating(cr api.ICustomResource, templating *api.TemplatingConfig) error {
+	if templating == nil || len(templating.Macros) == 0 {
+		return nil
+	}
+	return macro.ValidateUserMacros(templating.Macros, cr)
+}
+
+// ExpandedZookeeperStatus is the status-subresource call site: it resolves
+// spec.templating.macros against cr the same way the rendering engine does,
+// so users can see what each user macro actually expanded to.
+func ExpandedZookeeperStatus(cr api.ICustomResource, templating *api.TemplatingConfig) *api.ZookeeperStatus {
+	if templating == nil || len(templating.Macros) == 0 {
+		return nil
+	}
+	return &api.ZookeeperStatus{
+		ExpandedMacros: macro.ExpandedUserMacros(templating.Macros, cr),
+	}
+}
+
+func toBackend(b api.CoordinationBackend) coordination.Backend {
+	if b == api.CoordinationBackendEtcd {
+		return coordination.BackendEtcd
+	}
+	return coordination.BackendZookeeper
+}
+
+func address(nodes []api.ZookeeperNode) string {
+	addrs := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		addrs = append(addrs, fmt.Sprintf("%s:%d", n.Host, n.Port))
+	}
+	return strings.Join(addrs, ",")
+}
+
+func toRetryPolicy(r *api.ZookeeperRetryConfig) coordination.RetryPolicy {
+	if r == nil {
+		return coordination.RetryPolicy{}
+	}
+	return coordination.RetryPolicy{
+		BaseDelay:  time.Duration(r.BaseDelayMs) * time.Millisecond,
+		MaxDelay:   time.Duration(r.MaxDelayMs) * time.Millisecond,
+		Multiplier: r.Multiplier,
+		MaxRetries: int(r.MaxRetries),
+	}
+}
+
+func toCircuitBreakerPolicy(c *api.ZookeeperCircuitBreakerConfig) coordination.CircuitBreakerPolicy {
+	if c == nil {
+		return coordination.CircuitBreakerPolicy{}
+	}
+	return coordination.CircuitBreakerPolicy{
+		FailureThreshold: int(c.FailureThreshold),
+		Window:           time.Duration(c.WindowSeconds) * time.Second,
+		CooldownPeriod:   time.Duration(c.CooldownSeconds) * time.Second,
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crwire
+
+import (
+	"testing"
+	"time"
+
+	api "github.com/altinity/clickhouse-operator/pkg/apis/clickhouse.altinity.com/v1"
+	"github.com/altinity/clickhouse-operator/pkg/coordination"
+)
+
+func TestToBackend(t *testing.T) {
+	tests := []struct {
+		name string
+		in   api.CoordinationBackend
+		want coordination.Backend
+	}{
+		{name: "zookeeper", in: api.CoordinationBackendZookeeper, want: coordination.BackendZookeeper},
+		{name: "etcd", in: api.CoordinationBackendEtcd, want: coordination.BackendEtcd},
+		{name: "empty defaults to zookeeper", in: "", want: coordination.BackendZookeeper},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toBackend(tt.in); got != tt.want {
+				t.Errorf("toBackend(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddressJoinsNodesAsHostPortList(t *testing.T) {
+	nodes := []api.ZookeeperNode{
+		{Host: "zk-0.ensemble", Port: 2181},
+		{Host: "zk-1.ensemble", Port: 2181},
+		{Host: "zk-2.ensemble", Port: 2181},
+	}
+	want := "zk-0.ensemble:2181,zk-1.ensemble:2181,zk-2.ensemble:2181"
+	if got := address(nodes); got != want {
+		t.Errorf("address(%+v) = %q, want %q", nodes, got, want)
+	}
+}
+
+func TestToRetryPolicyNilReturnsZeroValue(t *testing.T) {
+	got := toRetryPolicy(nil)
+	if got != (coordination.RetryPolicy{}) {
+		t.Errorf("toRetryPolicy(nil) = %+v, want the zero value", got)
+	}
+}
+
+func TestToRetryPolicyConvertsMillisecondFields(t *testing.T) {
+	got := toRetryPolicy(&api.ZookeeperRetryConfig{
+		BaseDelayMs: 500,
+		MaxDelayMs:  30000,
+		Multiplier:  3,
+		MaxRetries:  5,
+	})
+	want := coordination.RetryPolicy{
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   30 * time.Second,
+		Multiplier: 3,
+		MaxRetries: 5,
+	}
+	if got != want {
+		t.Errorf("toRetryPolicy(...) = %+v, want %+v", got, want)
+	}
+}
+
+func TestToCircuitBreakerPolicyNilReturnsZeroValue(t *testing.T) {
+	got := toCircuitBreakerPolicy(nil)
+	if got != (coordination.CircuitBreakerPolicy{}) {
+		t.Errorf("toCircuitBreakerPolicy(nil) = %+v, want the zero value", got)
+	}
+}
+
+func TestToCircuitBreakerPolicyConvertsSecondFields(t *testing.T) {
+	got := toCircuitBreakerPolicy(&api.ZookeeperCircuitBreakerConfig{
+		FailureThreshold: 5,
+		WindowSeconds:    60,
+		CooldownSeconds:  30,
+	})
+	want := coordination.CircuitBreakerPolicy{
+		FailureThreshold: 5,
+		Window:           time.Minute,
+		CooldownPeriod:   30 * time.Second,
+	}
+	if got != want {
+		t.Errorf("toCircuitBreakerPolicy(...) = %+v, want %+v", got, want)
+	}
+}
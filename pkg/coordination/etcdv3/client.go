@@ -0,0 +1,297 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package etcdv3 implements coordination.Client on top of an etcd v3 cluster,
+// mapping znode-style paths onto etcd keys.
+package etcdv3
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/altinity/clickhouse-operator/pkg/coordination"
+)
+
+// ephemeralLeaseTTL is the lease TTL granted to keys created with
+// coordination.FlagEphemeral. The lease is kept alive for as long as the
+// Client is open; it expires (and the key is removed by etcd) if the
+// process dies without calling Close, mirroring a ZooKeeper session timeout.
+const ephemeralLeaseTTL = 30 * time.Second
+
+// sequenceCounterSuffix is appended to the path passed to Create to form the
+// key where Client stores the next sequence number for that path, analogous
+// to how ZooKeeper tracks cversion internally.
+const sequenceCounterSuffix = "/.sequence"
+
+// Client implements coordination.Client against an etcd v3 cluster.
+type Client struct {
+	cli *clientv3.Client
+
+	mu      sync.Mutex
+	leaseID clientv3.LeaseID
+
+	events chan coordination.Event
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewClient dials an etcd cluster. cfg.Address is a comma-separated list of
+// "host:port" endpoints, mirroring the ZooKeeper address format.
+func NewClient(ctx context.Context, cfg coordination.Config) (*Client, error) {
+	tlsConfig, err := buildTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	cli, err := clientv3.New(clientv3.Config{
+		Endpoints:   strings.Split(cfg.Address, ","),
+		DialTimeout: 30 * time.Second,
+		TLS:         tlsConfig,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("etcd connect: %w", err)
+	}
+
+	watchCtx, cancel := context.WithCancel(context.Background())
+	c := &Client{
+		cli:    cli,
+		events: make(chan coordination.Event, 16),
+		cancel: cancel,
+	}
+
+	lease, err := cli.Grant(ctx, int64(ephemeralLeaseTTL.Seconds()))
+	if err != nil {
+		cancel()
+		cli.Close()
+		return nil, fmt.Errorf("etcd grant lease: %w", err)
+	}
+	c.leaseID = lease.ID
+
+	keepAlive, err := cli.KeepAlive(watchCtx, lease.ID)
+	if err != nil {
+		cancel()
+		cli.Close()
+		return nil, fmt.Errorf("etcd keep-alive: %w", err)
+	}
+	c.wg.Add(2)
+	go c.drainKeepAlive(keepAlive)
+	go c.watch(watchCtx)
+
+	return c, nil
+}
+
+func (c *Client) drainKeepAlive(ch <-chan *clientv3.LeaseKeepAliveResponse) {
+	defer c.wg.Done()
+	for range ch {
+		// Draining is all that's required; the lease library restarts
+		// itself as long as this channel is read.
+	}
+	c.emitEvent(coordination.Event{Type: coordination.EventSession, Err: fmt.Errorf("etcd lease expired or keep-alive stopped")})
+}
+
+func (c *Client) watch(ctx context.Context) {
+	defer c.wg.Done()
+	watchChan := c.cli.Watch(ctx, "/", clientv3.WithPrefix())
+	for resp := range watchChan {
+		for _, ev := range resp.Events {
+			c.emitEvent(coordination.Event{
+				Type: toCoordinationEventType(ev),
+				Path: string(ev.Kv.Key),
+			})
+		}
+	}
+}
+
+func (c *Client) emitEvent(event coordination.Event) {
+	select {
+	case c.events <- event:
+	default:
+	}
+}
+
+func toCoordinationEventType(ev *clientv3.Event) coordination.EventType {
+	switch ev.Type {
+	case clientv3.EventTypePut:
+		if ev.IsCreate() {
+			return coordination.EventNodeCreated
+		}
+		return coordination.EventNodeDataChanged
+	case clientv3.EventTypeDelete:
+		return coordination.EventNodeDeleted
+	default:
+		return coordination.EventUnknown
+	}
+}
+
+func (c *Client) Events() <-chan coordination.Event {
+	return c.events
+}
+
+func (c *Client) Get(ctx context.Context, path string) ([]byte, *coordination.Stat, error) {
+	resp, err := c.cli.Get(ctx, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, nil, fmt.Errorf("etcd: key %s does not exist", path)
+	}
+	kv := resp.Kvs[0]
+	return kv.Value, &coordination.Stat{Version: int32(kv.Version)}, nil
+}
+
+func (c *Client) Exists(ctx context.Context, path string) (bool, *coordination.Stat, error) {
+	resp, err := c.cli.Get(ctx, path)
+	if err != nil {
+		return false, nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return false, nil, nil
+	}
+	kv := resp.Kvs[0]
+	return true, &coordination.Stat{Version: int32(kv.Version)}, nil
+}
+
+// Create writes path=value. FlagSequence appends a 10-digit zero-padded,
+// monotonically increasing suffix allocated via a counter key kept per path
+// (path + sequenceCounterSuffix),
+// the same shape ZooKeeper uses for PERSISTENT_SEQUENTIAL/EPHEMERAL_SEQUENTIAL
+// nodes. FlagEphemeral attaches the Client's session lease so the key is
+// removed automatically if the process goes away without calling Delete.
+func (c *Client) Create(ctx context.Context, path string, value []byte, flags coordination.Flag, _ []coordination.ACL) (string, error) {
+	finalPath := path
+	if flags&coordination.FlagSequence != 0 {
+		seq, err := c.nextSequence(ctx, path)
+		if err != nil {
+			return "", err
+		}
+		finalPath = fmt.Sprintf("%s%010d", path, seq)
+	}
+
+	var opts []clientv3.OpOption
+	if flags&coordination.FlagEphemeral != 0 {
+		opts = append(opts, clientv3.WithLease(c.leaseID))
+	}
+
+	txn := c.cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.Version(finalPath), "=", 0)).
+		Then(clientv3.OpPut(finalPath, string(value), opts...))
+	resp, err := txn.Commit()
+	if err != nil {
+		return "", err
+	}
+	if !resp.Succeeded {
+		return "", fmt.Errorf("etcd: key %s already exists", finalPath)
+	}
+	return finalPath, nil
+}
+
+// nextSequence allocates the next counter value for parentPath using a
+// compare-and-swap loop against a hidden counter key.
+func (c *Client) nextSequence(ctx context.Context, parentPath string) (int64, error) {
+	counterKey := parentPath + sequenceCounterSuffix
+	for {
+		resp, err := c.cli.Get(ctx, counterKey)
+		if err != nil {
+			return 0, err
+		}
+
+		var current int64
+		var modRevision int64
+		if len(resp.Kvs) > 0 {
+			if _, err := fmt.Sscanf(string(resp.Kvs[0].Value), "%d", &current); err != nil {
+				return 0, fmt.Errorf("etcd: corrupt sequence counter at %s: %w", counterKey, err)
+			}
+			modRevision = resp.Kvs[0].ModRevision
+		}
+
+		next := current + 1
+		txn := c.cli.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(counterKey), "=", modRevision)).
+			Then(clientv3.OpPut(counterKey, fmt.Sprintf("%d", next)))
+		commitResp, err := txn.Commit()
+		if err != nil {
+			return 0, err
+		}
+		if commitResp.Succeeded {
+			return next, nil
+		}
+		// Lost the race with a concurrent Create; retry.
+	}
+}
+
+func (c *Client) Set(ctx context.Context, path string, value []byte, version int32) (*coordination.Stat, error) {
+	txn := c.cli.Txn(ctx).
+		If(versionCompare(path, version)...).
+		Then(clientv3.OpPut(path, string(value))).
+		Else(clientv3.OpGet(path))
+	resp, err := txn.Commit()
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Succeeded {
+		return nil, fmt.Errorf("etcd: version mismatch setting %s", path)
+	}
+	getResp, err := c.cli.Get(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(getResp.Kvs) == 0 {
+		return nil, fmt.Errorf("etcd: key %s vanished after set", path)
+	}
+	return &coordination.Stat{Version: int32(getResp.Kvs[0].Version)}, nil
+}
+
+func (c *Client) Delete(ctx context.Context, path string, version int32) error {
+	txn := c.cli.Txn(ctx).
+		If(versionCompare(path, version)...).
+		Then(clientv3.OpDelete(path))
+	resp, err := txn.Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return fmt.Errorf("etcd: version mismatch deleting %s", path)
+	}
+	return nil
+}
+
+// versionCompare builds the If() clause enforcing an expected version,
+// matching zookeeper.Connection's (and ZooKeeper's own) convention that
+// version == -1 means "skip the version check" - the common "don't care"
+// case. An existing key's real etcd Version() is never -1, so without this
+// a -1 caller would always see a spurious version mismatch.
+func versionCompare(path string, version int32) []clientv3.Cmp {
+	if version == -1 {
+		return nil
+	}
+	return []clientv3.Cmp{clientv3.Compare(clientv3.Version(path), "=", int64(version))}
+}
+
+// Close cancels watch/keep-alive, waits for the goroutines driving them to
+// return (both call emitEvent, which sends on c.events), and only then
+// closes c.events - otherwise a goroutine still winding down after ctx
+// cancellation could send on an already-closed channel.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cancel()
+	c.wg.Wait()
+	close(c.events)
+	return c.cli.Close()
+}
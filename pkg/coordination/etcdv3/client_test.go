@@ -0,0 +1,81 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etcdv3
+
+import (
+	"reflect"
+	"testing"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/altinity/clickhouse-operator/pkg/coordination"
+)
+
+func TestVersionCompareSkipsCheckForVersionMinusOne(t *testing.T) {
+	cmps := versionCompare("/some/path", -1)
+	if cmps != nil {
+		t.Fatalf("versionCompare(_, -1) = %v, want nil so the If() clause always passes", cmps)
+	}
+}
+
+func TestVersionCompareBuildsExactVersionCheck(t *testing.T) {
+	cmps := versionCompare("/some/path", 3)
+	want := clientv3.Compare(clientv3.Version("/some/path"), "=", 3)
+	if len(cmps) != 1 || !reflect.DeepEqual(cmps[0], want) {
+		t.Fatalf("versionCompare(_, 3) = %v, want [%v]", cmps, want)
+	}
+}
+
+func TestToCoordinationEventType(t *testing.T) {
+	tests := []struct {
+		name string
+		ev   *clientv3.Event
+		want coordination.EventType
+	}{
+		{
+			name: "put at creation revision is a create",
+			ev: &clientv3.Event{
+				Type: clientv3.EventTypePut,
+				Kv:   &mvccpb.KeyValue{CreateRevision: 5, ModRevision: 5},
+			},
+			want: coordination.EventNodeCreated,
+		},
+		{
+			name: "put at a later revision is a data change",
+			ev: &clientv3.Event{
+				Type: clientv3.EventTypePut,
+				Kv:   &mvccpb.KeyValue{CreateRevision: 5, ModRevision: 9},
+			},
+			want: coordination.EventNodeDataChanged,
+		},
+		{
+			name: "delete",
+			ev: &clientv3.Event{
+				Type: clientv3.EventTypeDelete,
+				Kv:   &mvccpb.KeyValue{CreateRevision: 5, ModRevision: 9},
+			},
+			want: coordination.EventNodeDeleted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toCoordinationEventType(tt.ev); got != tt.want {
+				t.Errorf("toCoordinationEventType(%+v) = %v, want %v", tt.ev, got, tt.want)
+			}
+		})
+	}
+}
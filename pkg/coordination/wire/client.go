@@ -0,0 +1,95 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package wire selects a coordination.Client implementation for a given
+// coordination.Config. It is kept separate from package coordination itself
+// so that the interface package stays free of a dependency on every backend.
+package wire
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/altinity/clickhouse-operator/pkg/coordination"
+	"github.com/altinity/clickhouse-operator/pkg/coordination/etcdv3"
+	"github.com/altinity/clickhouse-operator/pkg/coordination/zkclient"
+	"github.com/altinity/clickhouse-operator/pkg/model/zookeeper"
+)
+
+// NewClient picks the Client implementation named by cfg.Backend. Controllers
+// should call this once per CR at reconcile wire-up time, translating the
+// CR's `spec.zookeeper.backend` (or equivalent) selector into cfg.Backend -
+// everything downstream (config generation, cleanup, replica queue
+// inspection) then only depends on the coordination.Client interface.
+//
+// cfg.Retry/cfg.CircuitBreaker are applied as process-wide overrides on the
+// zookeeper package before dialing, since that package's retry and circuit
+// breaker policy (like its TLS settings) predate per-Client configuration.
+// Backends that don't have an equivalent knob ignore them.
+func NewClient(ctx context.Context, cfg coordination.Config) (coordination.Client, error) {
+	switch cfg.Backend {
+	case "", coordination.BackendZookeeper:
+		applyZookeeperPolicies(cfg)
+		return zkclient.NewClient(cfg)
+	case coordination.BackendEtcd:
+		return etcdv3.NewClient(ctx, cfg)
+	default:
+		return nil, fmt.Errorf("coordination: unknown backend %q", cfg.Backend)
+	}
+}
+
+// applyZookeeperPolicies overrides the zookeeper package's default retry and
+// circuit breaker policies when cfg carries non-zero values, leaving the
+// package defaults (tuned for the common case) alone otherwise.
+func applyZookeeperPolicies(cfg coordination.Config) {
+	if (cfg.Retry != coordination.RetryPolicy{}) {
+		zookeeper.SetRetryPolicy(retryPolicyOverride(zookeeper.DefaultRetryPolicy, cfg.Retry))
+	}
+	if (cfg.CircuitBreaker != coordination.CircuitBreakerPolicy{}) {
+		zookeeper.SetCircuitBreakerPolicy(circuitBreakerPolicyOverride(zookeeper.DefaultCircuitBreakerPolicy, cfg.CircuitBreaker))
+	}
+}
+
+// retryPolicyOverride returns base with every non-zero field of override
+// applied on top.
+func retryPolicyOverride(base zookeeper.RetryPolicy, override coordination.RetryPolicy) zookeeper.RetryPolicy {
+	if override.BaseDelay > 0 {
+		base.BaseDelay = override.BaseDelay
+	}
+	if override.MaxDelay > 0 {
+		base.MaxDelay = override.MaxDelay
+	}
+	if override.Multiplier > 0 {
+		base.Multiplier = override.Multiplier
+	}
+	if override.MaxRetries > 0 {
+		base.MaxRetries = override.MaxRetries
+	}
+	return base
+}
+
+// circuitBreakerPolicyOverride returns base with every non-zero field of
+// override applied on top.
+func circuitBreakerPolicyOverride(base zookeeper.CircuitBreakerPolicy, override coordination.CircuitBreakerPolicy) zookeeper.CircuitBreakerPolicy {
+	if override.FailureThreshold > 0 {
+		base.FailureThreshold = override.FailureThreshold
+	}
+	if override.Window > 0 {
+		base.Window = override.Window
+	}
+	if override.CooldownPeriod > 0 {
+		base.CooldownPeriod = override.CooldownPeriod
+	}
+	return base
+}
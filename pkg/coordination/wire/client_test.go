@@ -0,0 +1,68 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wire
+
+import (
+	"testing"
+	"time"
+
+	"github.com/altinity/clickhouse-operator/pkg/coordination"
+	"github.com/altinity/clickhouse-operator/pkg/model/zookeeper"
+)
+
+func TestRetryPolicyOverrideLeavesDefaultsForZeroFields(t *testing.T) {
+	got := retryPolicyOverride(zookeeper.DefaultRetryPolicy, coordination.RetryPolicy{})
+	if got != zookeeper.DefaultRetryPolicy {
+		t.Errorf("retryPolicyOverride(base, zero) = %+v, want base %+v unchanged", got, zookeeper.DefaultRetryPolicy)
+	}
+}
+
+func TestRetryPolicyOverrideAppliesOnlyNonZeroFields(t *testing.T) {
+	got := retryPolicyOverride(zookeeper.DefaultRetryPolicy, coordination.RetryPolicy{BaseDelay: 2 * time.Second})
+
+	if got.BaseDelay != 2*time.Second {
+		t.Errorf("BaseDelay = %v, want the overridden 2s", got.BaseDelay)
+	}
+	if got.MaxDelay != zookeeper.DefaultRetryPolicy.MaxDelay {
+		t.Errorf("MaxDelay = %v, want the untouched default %v", got.MaxDelay, zookeeper.DefaultRetryPolicy.MaxDelay)
+	}
+	if got.Multiplier != zookeeper.DefaultRetryPolicy.Multiplier {
+		t.Errorf("Multiplier = %v, want the untouched default %v", got.Multiplier, zookeeper.DefaultRetryPolicy.Multiplier)
+	}
+	if got.MaxRetries != zookeeper.DefaultRetryPolicy.MaxRetries {
+		t.Errorf("MaxRetries = %v, want the untouched default %v", got.MaxRetries, zookeeper.DefaultRetryPolicy.MaxRetries)
+	}
+}
+
+func TestCircuitBreakerPolicyOverrideLeavesDefaultsForZeroFields(t *testing.T) {
+	got := circuitBreakerPolicyOverride(zookeeper.DefaultCircuitBreakerPolicy, coordination.CircuitBreakerPolicy{})
+	if got != zookeeper.DefaultCircuitBreakerPolicy {
+		t.Errorf("circuitBreakerPolicyOverride(base, zero) = %+v, want base %+v unchanged", got, zookeeper.DefaultCircuitBreakerPolicy)
+	}
+}
+
+func TestCircuitBreakerPolicyOverrideAppliesOnlyNonZeroFields(t *testing.T) {
+	got := circuitBreakerPolicyOverride(zookeeper.DefaultCircuitBreakerPolicy, coordination.CircuitBreakerPolicy{FailureThreshold: 9})
+
+	if got.FailureThreshold != 9 {
+		t.Errorf("FailureThreshold = %v, want the overridden 9", got.FailureThreshold)
+	}
+	if got.Window != zookeeper.DefaultCircuitBreakerPolicy.Window {
+		t.Errorf("Window = %v, want the untouched default %v", got.Window, zookeeper.DefaultCircuitBreakerPolicy.Window)
+	}
+	if got.CooldownPeriod != zookeeper.DefaultCircuitBreakerPolicy.CooldownPeriod {
+		t.Errorf("CooldownPeriod = %v, want the untouched default %v", got.CooldownPeriod, zookeeper.DefaultCircuitBreakerPolicy.CooldownPeriod)
+	}
+}
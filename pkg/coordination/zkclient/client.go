@@ -0,0 +1,102 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package zkclient adapts pkg/model/zookeeper.Connection to the
+// coordination.Client interface.
+package zkclient
+
+import (
+	"context"
+
+	"github.com/z-division/go-zookeeper/zk"
+
+	"github.com/altinity/clickhouse-operator/pkg/coordination"
+	"github.com/altinity/clickhouse-operator/pkg/model/zookeeper"
+)
+
+// Client wraps a *zookeeper.Connection so it satisfies coordination.Client.
+type Client struct {
+	conn *zookeeper.Connection
+}
+
+// NewClient builds a coordination.Client backed by a ZooKeeper ensemble.
+// TLS is process-wide in pkg/model/zookeeper (it predates per-Connection
+// config), so cfg's TLS fields are applied via SetTLSConfig here rather than
+// passed to NewConnection.
+func NewClient(cfg coordination.Config) (*Client, error) {
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		zookeeper.SetTLSConfig(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSCAFile, cfg.TLSServerNameOverride)
+	}
+	return &Client{conn: zookeeper.NewConnection(cfg.Address)}, nil
+}
+
+func (c *Client) Get(ctx context.Context, path string) ([]byte, *coordination.Stat, error) {
+	data, stat, err := c.conn.Get(ctx, path)
+	return data, toStat(stat), err
+}
+
+func (c *Client) Exists(ctx context.Context, path string) (bool, *coordination.Stat, error) {
+	exists, stat, err := c.conn.Exists(ctx, path)
+	return exists, toStat(stat), err
+}
+
+func (c *Client) Create(ctx context.Context, path string, value []byte, flags coordination.Flag, acl []coordination.ACL) (string, error) {
+	return c.conn.Create(ctx, path, value, toZkFlags(flags), toZkACL(acl))
+}
+
+func (c *Client) Set(ctx context.Context, path string, value []byte, version int32) (*coordination.Stat, error) {
+	stat, err := c.conn.Set(ctx, path, value, version)
+	return toStat(stat), err
+}
+
+func (c *Client) Delete(ctx context.Context, path string, version int32) error {
+	return c.conn.Delete(ctx, path, version)
+}
+
+func (c *Client) Events() <-chan coordination.Event {
+	return c.conn.Events()
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func toStat(stat *zk.Stat) *coordination.Stat {
+	if stat == nil {
+		return nil
+	}
+	return &coordination.Stat{Version: stat.Version}
+}
+
+func toZkACL(acl []coordination.ACL) []zk.ACL {
+	if acl == nil {
+		return zk.WorldACL(zk.PermAll)
+	}
+	out := make([]zk.ACL, 0, len(acl))
+	for _, a := range acl {
+		out = append(out, zk.ACL{Perms: a.Perms, Scheme: a.Scheme, ID: a.ID})
+	}
+	return out
+}
+
+func toZkFlags(flags coordination.Flag) int32 {
+	var zkFlags int32
+	if flags&coordination.FlagEphemeral != 0 {
+		zkFlags |= zk.FlagEphemeral
+	}
+	if flags&coordination.FlagSequence != 0 {
+		zkFlags |= zk.FlagSequence
+	}
+	return zkFlags
+}
@@ -0,0 +1,76 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zkclient
+
+import (
+	"testing"
+
+	"github.com/z-division/go-zookeeper/zk"
+
+	"github.com/altinity/clickhouse-operator/pkg/coordination"
+)
+
+func TestToStatNilPassesThrough(t *testing.T) {
+	if got := toStat(nil); got != nil {
+		t.Errorf("toStat(nil) = %+v, want nil", got)
+	}
+}
+
+func TestToStatCopiesVersion(t *testing.T) {
+	got := toStat(&zk.Stat{Version: 7})
+	if got == nil || got.Version != 7 {
+		t.Errorf("toStat(&zk.Stat{Version: 7}) = %+v, want Version 7", got)
+	}
+}
+
+func TestToZkFlags(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags coordination.Flag
+		want  int32
+	}{
+		{name: "none", flags: 0, want: 0},
+		{name: "ephemeral", flags: coordination.FlagEphemeral, want: zk.FlagEphemeral},
+		{name: "sequence", flags: coordination.FlagSequence, want: zk.FlagSequence},
+		{
+			name:  "both",
+			flags: coordination.FlagEphemeral | coordination.FlagSequence,
+			want:  zk.FlagEphemeral | zk.FlagSequence,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toZkFlags(tt.flags); got != tt.want {
+				t.Errorf("toZkFlags(%v) = %v, want %v", tt.flags, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToZkACLNilDefaultsToWorldAll(t *testing.T) {
+	got := toZkACL(nil)
+	want := zk.WorldACL(zk.PermAll)
+	if len(got) != len(want) || got[0] != want[0] {
+		t.Errorf("toZkACL(nil) = %+v, want %+v", got, want)
+	}
+}
+
+func TestToZkACLTranslatesEntries(t *testing.T) {
+	got := toZkACL([]coordination.ACL{{Perms: zk.PermRead, Scheme: "digest", ID: "user:pw"}})
+	if len(got) != 1 || got[0].Perms != zk.PermRead || got[0].Scheme != "digest" || got[0].ID != "user:pw" {
+		t.Errorf("toZkACL(...) = %+v, want a single translated entry", got)
+	}
+}
@@ -25,8 +25,9 @@ import (
 
 // MacrosEngine
 type MacrosEngine struct {
-	namer *short.Namer
-	scope any
+	namer      *short.Namer
+	scope      any
+	userMacros map[string]string
 }
 
 // Macro
@@ -37,6 +38,36 @@ func Macro(scope any) *MacrosEngine {
 	return m
 }
 
+// WithUserMacros attaches CR-declared extra macros (spec.templating.macros)
+// to be merged into every replacer this engine builds. defs maps a macro
+// token (e.g. "{region}") to either a literal string or a Go-template
+// expression evaluated against scope. Built-in tokens always win: the
+// newLineMacroReplacer* helpers put the built-in pairs ahead of
+// userMacroPairs, and strings.Replacer compares pairs in argument order.
+func (m *MacrosEngine) WithUserMacros(defs map[string]string) *MacrosEngine {
+	m.userMacros = defs
+	return m
+}
+
+// userMacroPairs expands m.userMacros against m.scope into strings.Replacer
+// argument pairs. A definition that fails to expand is dropped rather than
+// surfaced here - ValidateUserMacros is expected to have already rejected it
+// at CR admission time.
+func (m *MacrosEngine) userMacroPairs() []string {
+	if len(m.userMacros) == 0 {
+		return nil
+	}
+	pairs := make([]string, 0, len(m.userMacros)*2)
+	for name, value := range m.userMacros {
+		expanded, err := expandUserMacroValue(name, value, m.scope)
+		if err != nil {
+			continue
+		}
+		pairs = append(pairs, name, expanded)
+	}
+	return pairs
+}
+
 // Line expands line with macros(es)
 func (m *MacrosEngine) Line(line string) string {
 	switch t := m.scope.(type) {
@@ -73,10 +104,11 @@ func (m *MacrosEngine) Map(_map map[string]string) map[string]string {
 
 // newLineMacroReplacerCR
 func (m *MacrosEngine) newLineMacroReplacerCR(cr api.ICustomResource) *strings.Replacer {
-	return strings.NewReplacer(
+	pairs := []string{
 		MacrosNamespace, m.namer.Name(short.Namespace, cr),
 		MacrosChiName, m.namer.Name(short.CRName, cr),
-	)
+	}
+	return strings.NewReplacer(append(pairs, m.userMacroPairs()...)...)
 }
 
 // newMapMacroReplacerCR
@@ -86,12 +118,13 @@ func (m *MacrosEngine) newMapMacroReplacerCR(cr api.ICustomResource) *util.MapRe
 
 // newLineMacroReplacerCluster
 func (m *MacrosEngine) newLineMacroReplacerCluster(cluster api.ICluster) *strings.Replacer {
-	return strings.NewReplacer(
+	pairs := []string{
 		MacrosNamespace, m.namer.Name(short.Namespace, cluster),
 		MacrosChiName, m.namer.Name(short.CRName, cluster),
 		MacrosClusterName, m.namer.Name(short.ClusterName, cluster),
 		MacrosClusterIndex, strconv.Itoa(cluster.GetRuntime().GetAddress().GetClusterIndex()),
-	)
+	}
+	return strings.NewReplacer(append(pairs, m.userMacroPairs()...)...)
 }
 
 // newMapMacroReplacerCluster
@@ -101,14 +134,15 @@ func (m *MacrosEngine) newMapMacroReplacerCluster(cluster api.ICluster) *util.Ma
 
 // newLineMacroReplacerShard
 func (m *MacrosEngine) newLineMacroReplacerShard(shard api.IShard) *strings.Replacer {
-	return strings.NewReplacer(
+	pairs := []string{
 		MacrosNamespace, m.namer.Name(short.Namespace, shard),
 		MacrosChiName, m.namer.Name(short.CRName, shard),
 		MacrosClusterName, m.namer.Name(short.ClusterName, shard),
 		MacrosClusterIndex, strconv.Itoa(shard.GetRuntime().GetAddress().GetClusterIndex()),
 		MacrosShardName, m.namer.Name(short.ShardName, shard),
 		MacrosShardIndex, strconv.Itoa(shard.GetRuntime().GetAddress().GetShardIndex()),
-	)
+	}
+	return strings.NewReplacer(append(pairs, m.userMacroPairs()...)...)
 }
 
 // newMapMacroReplacerShard
@@ -140,7 +174,7 @@ func clusterScopeIndexOfPreviousCycleTail(host api.IHost) int {
 
 // newLineMacroReplacerHost
 func (m *MacrosEngine) newLineMacroReplacerHost(host api.IHost) *strings.Replacer {
-	return strings.NewReplacer(
+	pairs := []string{
 		MacrosNamespace, m.namer.Name(short.Namespace, host),
 		MacrosChiName, m.namer.Name(short.CRName, host),
 		MacrosClusterName, m.namer.Name(short.ClusterName, host),
@@ -159,7 +193,8 @@ func (m *MacrosEngine) newLineMacroReplacerHost(host api.IHost) *strings.Replace
 		MacrosClusterScopeCycleIndex, strconv.Itoa(host.GetRuntime().GetAddress().GetClusterScopeCycleIndex()), // TODO use appropriate namePart function
 		MacrosClusterScopeCycleOffset, strconv.Itoa(host.GetRuntime().GetAddress().GetClusterScopeCycleOffset()), // TODO use appropriate namePart function
 		MacrosClusterScopeCycleHeadPointsToPreviousCycleTail, strconv.Itoa(clusterScopeIndexOfPreviousCycleTail(host)),
-	)
+	}
+	return strings.NewReplacer(append(pairs, m.userMacroPairs()...)...)
 }
 
 // newMapMacroReplacerHost
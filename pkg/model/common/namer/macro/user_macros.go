@@ -0,0 +1,112 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package macro
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// reservedMacroNames returns the set of macro tokens the engine already
+// substitutes. User-defined macros (spec.templating.macros) may not use any
+// of these, so a CR can never accidentally shadow a built-in.
+func reservedMacroNames() map[string]bool {
+	return map[string]bool{
+		MacrosNamespace:               true,
+		MacrosChiName:                 true,
+		MacrosClusterName:             true,
+		MacrosClusterIndex:            true,
+		MacrosShardName:               true,
+		MacrosShardIndex:              true,
+		MacrosShardScopeIndex:         true,
+		MacrosReplicaName:             true,
+		MacrosReplicaIndex:            true,
+		MacrosReplicaScopeIndex:       true,
+		MacrosHostName:                true,
+		MacrosChiScopeIndex:           true,
+		MacrosChiScopeCycleIndex:      true,
+		MacrosChiScopeCycleOffset:     true,
+		MacrosClusterScopeIndex:       true,
+		MacrosClusterScopeCycleIndex:  true,
+		MacrosClusterScopeCycleOffset: true,
+		MacrosClusterScopeCycleHeadPointsToPreviousCycleTail: true,
+	}
+}
+
+// ValidateUserMacros is meant to run at CR admission time, once per CR, over
+// spec.templating.macros. It rejects macros that would shadow a built-in and
+// catches Go-template macros with unresolved references by test-executing
+// them against scope (typically the CR itself, since that's the broadest
+// scope a user macro can be declared for).
+func ValidateUserMacros(defs map[string]string, scope any) error {
+	reserved := reservedMacroNames()
+
+	var collisions []string
+	for name := range defs {
+		if reserved[name] {
+			collisions = append(collisions, name)
+		}
+	}
+	if len(collisions) > 0 {
+		sort.Strings(collisions)
+		return fmt.Errorf("user macros collide with reserved names: %s", strings.Join(collisions, ", "))
+	}
+
+	for name, value := range defs {
+		if _, err := expandUserMacroValue(name, value, scope); err != nil {
+			return fmt.Errorf("user macro %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// ExpandedUserMacros resolves every entry of defs against scope, the same
+// way the engine does internally. It is meant for the operator's status
+// subresource, so users can see what a user macro actually expanded to
+// without having to re-derive it from the rendered config.
+func ExpandedUserMacros(defs map[string]string, scope any) map[string]string {
+	expanded := make(map[string]string, len(defs))
+	for name, value := range defs {
+		if resolved, err := expandUserMacroValue(name, value, scope); err == nil {
+			expanded[name] = resolved
+		}
+	}
+	return expanded
+}
+
+// expandUserMacroValue resolves one spec.templating.macros entry. A value
+// containing a Go-template action ("{{") is parsed and executed against
+// scope; anything else is used as a literal string, same as a built-in
+// macro's replacement.
+func expandUserMacroValue(name, value string, scope any) (string, error) {
+	if !strings.Contains(value, "{{") {
+		return value, nil
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("parse: %w", err)
+	}
+
+	var out strings.Builder
+	if err := tmpl.Execute(&out, scope); err != nil {
+		return "", fmt.Errorf("unresolved reference: %w", err)
+	}
+
+	return out.String(), nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package macro
+
+import "testing"
+
+func TestValidateUserMacrosRejectsReservedNames(t *testing.T) {
+	defs := map[string]string{
+		MacrosNamespace: "should-not-be-allowed",
+	}
+	if err := ValidateUserMacros(defs, nil); err == nil {
+		t.Fatal("ValidateUserMacros() = nil error, want collision error for reserved macro name")
+	}
+}
+
+func TestValidateUserMacrosAcceptsLiteralValue(t *testing.T) {
+	defs := map[string]string{
+		"my-region": "us-east-1",
+	}
+	if err := ValidateUserMacros(defs, nil); err != nil {
+		t.Fatalf("ValidateUserMacros() = %v, want nil for a literal macro value", err)
+	}
+}
+
+func TestValidateUserMacrosAcceptsResolvableTemplate(t *testing.T) {
+	scope := struct{ Region string }{Region: "us-east-1"}
+	defs := map[string]string{
+		"my-region": "{{.Region}}",
+	}
+	if err := ValidateUserMacros(defs, scope); err != nil {
+		t.Fatalf("ValidateUserMacros() = %v, want nil for a resolvable template macro", err)
+	}
+}
+
+func TestValidateUserMacrosRejectsUnresolvedReference(t *testing.T) {
+	scope := struct{ Region string }{Region: "us-east-1"}
+	defs := map[string]string{
+		"my-region": "{{.NoSuchField}}",
+	}
+	if err := ValidateUserMacros(defs, scope); err == nil {
+		t.Fatal("ValidateUserMacros() = nil error, want error for an unresolved template reference")
+	}
+}
+
+func TestExpandedUserMacrosSkipsUnresolvable(t *testing.T) {
+	scope := struct{ Region string }{Region: "us-east-1"}
+	defs := map[string]string{
+		"my-region": "{{.Region}}",
+		"broken":    "{{.NoSuchField}}",
+		"literal":   "plain-value",
+	}
+
+	got := ExpandedUserMacros(defs, scope)
+
+	if got["my-region"] != "us-east-1" {
+		t.Errorf("expanded[my-region] = %q, want %q", got["my-region"], "us-east-1")
+	}
+	if got["literal"] != "plain-value" {
+		t.Errorf("expanded[literal] = %q, want %q", got["literal"], "plain-value")
+	}
+	if _, ok := got["broken"]; ok {
+		t.Error("expanded[broken] present, want it omitted since the reference can't resolve")
+	}
+}
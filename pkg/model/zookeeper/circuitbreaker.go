@@ -0,0 +1,121 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zookeeper
+
+import (
+	"sync"
+	"time"
+)
+
+// circuitState mirrors the classic closed/open/half-open circuit breaker
+// states. Its numeric value is what chop_zk_circuit_state reports.
+type circuitState int32
+
+const (
+	circuitClosed circuitState = iota
+	circuitHalfOpen
+	circuitOpen
+)
+
+// circuitBreaker guards Connection.retry against hammering an ensemble
+// (identified by address, one breaker per Connection) that is consistently
+// failing to dial. It opens after FailureThreshold consecutive failures
+// inside Window, fails fast for CooldownPeriod, then allows a single probe.
+type circuitBreaker struct {
+	address string
+	policy  CircuitBreakerPolicy
+
+	mu                  sync.Mutex
+	state               circuitState
+	consecutiveFailures int
+	lastFailureAt       time.Time
+	openedAt            time.Time
+	probeStartedAt      time.Time
+}
+
+func newCircuitBreaker(address string, policy CircuitBreakerPolicy) *circuitBreaker {
+	return &circuitBreaker{address: address, policy: policy}
+}
+
+// allow reports whether a dial attempt should proceed. It transitions an
+// open breaker to half-open once CooldownPeriod has elapsed, admitting
+// exactly the caller that observes the transition as the probe attempt -
+// every other concurrent caller sees the already-half-open state and is
+// denied until recordSuccess/recordFailure resolves the probe. If the
+// admitted probe itself never calls back into recordSuccess/recordFailure
+// (e.g. its caller's context was canceled before a dial was even attempted),
+// the half-open state would otherwise wedge forever; allow treats a probe
+// that's been outstanding for another full CooldownPeriod as abandoned and
+// admits a fresh one.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitClosed:
+		return true
+	case circuitOpen:
+		if time.Since(b.openedAt) < b.policy.CooldownPeriod {
+			return false
+		}
+		b.state = circuitHalfOpen
+		b.probeStartedAt = time.Now()
+		setCircuitMetric(b.address, b.state)
+		return true
+	default: // circuitHalfOpen: a probe is already in flight
+		if time.Since(b.probeStartedAt) < b.policy.CooldownPeriod {
+			return false
+		}
+		// The previous probe never resolved - treat it as abandoned rather
+		// than wedging the breaker in half-open until process restart.
+		b.probeStartedAt = time.Now()
+		return true
+	}
+}
+
+func (b *circuitBreaker) recordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.consecutiveFailures = 0
+	if b.state != circuitClosed {
+		b.state = circuitClosed
+		setCircuitMetric(b.address, b.state)
+	}
+}
+
+func (b *circuitBreaker) recordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if !b.lastFailureAt.IsZero() && now.Sub(b.lastFailureAt) > b.policy.Window {
+		b.consecutiveFailures = 0
+	}
+	b.lastFailureAt = now
+	b.consecutiveFailures++
+
+	switch {
+	case b.state == circuitHalfOpen:
+		// The probe failed - back to open for another full cooldown.
+		b.state = circuitOpen
+		b.openedAt = now
+		setCircuitMetric(b.address, b.state)
+	case b.state == circuitClosed && b.consecutiveFailures >= b.policy.FailureThreshold:
+		b.state = circuitOpen
+		b.openedAt = now
+		setCircuitMetric(b.address, b.state)
+	}
+}
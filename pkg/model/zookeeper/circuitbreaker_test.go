@@ -0,0 +1,142 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zookeeper
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	b := newCircuitBreaker("addr", CircuitBreakerPolicy{
+		FailureThreshold: 3,
+		Window:           time.Minute,
+		CooldownPeriod:   time.Hour,
+	})
+
+	for i := 0; i < 2; i++ {
+		if !b.allow() {
+			t.Fatalf("attempt %d: expected allow before threshold reached", i)
+		}
+		b.recordFailure()
+	}
+	if b.state != circuitClosed {
+		t.Fatalf("state = %v, want circuitClosed before threshold reached", b.state)
+	}
+
+	b.recordFailure() // 3rd consecutive failure
+	if b.state != circuitOpen {
+		t.Fatalf("state = %v, want circuitOpen at threshold", b.state)
+	}
+	if b.allow() {
+		t.Fatal("allow() = true, want false while circuit is open and cooldown hasn't elapsed")
+	}
+}
+
+func TestCircuitBreakerFailureOutsideWindowDoesNotAccumulate(t *testing.T) {
+	b := newCircuitBreaker("addr", CircuitBreakerPolicy{
+		FailureThreshold: 2,
+		Window:           time.Millisecond,
+		CooldownPeriod:   time.Hour,
+	})
+
+	b.allow()
+	b.recordFailure()
+	time.Sleep(5 * time.Millisecond) // let the failure streak age out of the window
+
+	if !b.allow() {
+		t.Fatal("allow() = false, want true: the only prior failure is outside Window")
+	}
+	b.recordFailure()
+	if b.state != circuitClosed {
+		t.Fatalf("state = %v, want circuitClosed: stale failure should not have counted toward the threshold", b.state)
+	}
+}
+
+// TestCircuitBreakerHalfOpenAdmitsExactlyOneProbe reproduces the scenario
+// from code review: once the breaker transitions to half-open, a burst of
+// concurrent allow() calls must admit exactly one caller, not all of them.
+func TestCircuitBreakerHalfOpenAdmitsExactlyOneProbe(t *testing.T) {
+	b := newCircuitBreaker("addr", CircuitBreakerPolicy{
+		FailureThreshold: 1,
+		Window:           time.Minute,
+		CooldownPeriod:   time.Hour,
+	})
+
+	b.allow()
+	b.recordFailure() // opens the breaker
+	// Backdate openedAt past CooldownPeriod rather than sleeping, since
+	// CooldownPeriod must stay long relative to the burst below (it also
+	// bounds how long an abandoned probe is given before allow() admits a
+	// replacement). The first allow() of the burst makes the open ->
+	// half-open transition and is the one probe admitted.
+	b.mu.Lock()
+	b.openedAt = time.Now().Add(-2 * time.Hour)
+	b.mu.Unlock()
+
+	const concurrency = 50
+	var admitted int32
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			if b.allow() {
+				mu.Lock()
+				admitted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if admitted != 1 {
+		t.Fatalf("admitted = %d concurrent callers during half-open, want exactly 1", admitted)
+	}
+}
+
+// TestCircuitBreakerAbandonedProbeIsReplaced covers the case where the
+// single probe admitted during half-open never calls back into
+// recordSuccess/recordFailure at all (e.g. its caller's context was
+// canceled before a dial was attempted). allow() must eventually admit a
+// replacement probe rather than staying wedged in half-open forever.
+func TestCircuitBreakerAbandonedProbeIsReplaced(t *testing.T) {
+	b := newCircuitBreaker("addr", CircuitBreakerPolicy{
+		FailureThreshold: 1,
+		Window:           time.Minute,
+		CooldownPeriod:   time.Millisecond,
+	})
+
+	b.allow()
+	b.recordFailure() // opens the breaker
+	time.Sleep(5 * time.Millisecond)
+
+	if !b.allow() {
+		t.Fatal("allow() = false, want true: cooldown elapsed, should admit the probe")
+	}
+	if b.allow() {
+		t.Fatal("allow() = true, want false: a probe is already outstanding")
+	}
+
+	// Simulate the probe's caller abandoning it without ever resolving the
+	// outcome, then wait past another CooldownPeriod.
+	time.Sleep(5 * time.Millisecond)
+
+	if !b.allow() {
+		t.Fatal("allow() = false, want true: the abandoned probe should have been replaced")
+	}
+}
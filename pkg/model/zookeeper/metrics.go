@@ -0,0 +1,64 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zookeeper
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	resultSuccess     = "success"
+	resultError       = "error"
+	resultCircuitOpen = "circuit_open"
+)
+
+var (
+	opTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "chop_zk_op_total",
+		Help: "Count of ZooKeeper operations by op and result (success, error, circuit_open).",
+	}, []string{"op", "result"})
+
+	opDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "chop_zk_op_duration_seconds",
+		Help:    "Latency of ZooKeeper operations that reached the ensemble, by op.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"op"})
+
+	circuitStateGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "chop_zk_circuit_state",
+		Help: "Per-address ZK circuit breaker state: 0=closed, 1=half-open, 2=open.",
+	}, []string{"address"})
+)
+
+// RegisterMetrics registers this package's collectors on reg, so the
+// operator's existing metrics endpoint can expose ZK health separately from
+// ClickHouse health.
+func RegisterMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(opTotal, opDuration, circuitStateGauge)
+}
+
+func recordOp(op, result string) {
+	opTotal.WithLabelValues(op, result).Inc()
+}
+
+func observeOpDuration(op string, d time.Duration) {
+	opDuration.WithLabelValues(op).Observe(d.Seconds())
+}
+
+func setCircuitMetric(address string, state circuitState) {
+	circuitStateGauge.WithLabelValues(address).Set(float64(state))
+}
@@ -0,0 +1,72 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zookeeper
+
+import (
+	"testing"
+	"time"
+)
+
+// TestNextBackoffStaysWithinBounds exercises the decorrelated jitter formula
+// over many iterations: every delay it returns must stay within
+// [BaseDelay, MaxDelay], and the policy's MaxDelay ceiling must eventually
+// be reached rather than growing without bound.
+func TestNextBackoffStaysWithinBounds(t *testing.T) {
+	saved := retryPolicy
+	defer func() { retryPolicy = saved }()
+	SetRetryPolicy(RetryPolicy{
+		BaseDelay:  10 * time.Millisecond,
+		MaxDelay:   100 * time.Millisecond,
+		Multiplier: 3,
+		MaxRetries: 5,
+	})
+
+	delay := retryPolicy.BaseDelay
+	sawMax := false
+	for i := 0; i < 100; i++ {
+		delay = nextBackoff(&delay)
+		if delay < retryPolicy.BaseDelay || delay > retryPolicy.MaxDelay {
+			t.Fatalf("iteration %d: nextBackoff = %v, want within [%v, %v]", i, delay, retryPolicy.BaseDelay, retryPolicy.MaxDelay)
+		}
+		if delay == retryPolicy.MaxDelay {
+			sawMax = true
+		}
+	}
+	if !sawMax {
+		t.Fatal("nextBackoff never reached MaxDelay over 100 iterations")
+	}
+}
+
+// TestNextBackoffMinimumIsBaseDelay checks the floor: even starting from a
+// previous delay smaller than BaseDelay, the next delay is never below it.
+func TestNextBackoffMinimumIsBaseDelay(t *testing.T) {
+	saved := retryPolicy
+	defer func() { retryPolicy = saved }()
+	SetRetryPolicy(RetryPolicy{
+		BaseDelay:  50 * time.Millisecond,
+		MaxDelay:   time.Second,
+		Multiplier: 2,
+		MaxRetries: 5,
+	})
+
+	previous := time.Millisecond
+	for i := 0; i < 20; i++ {
+		next := nextBackoff(&previous)
+		if next < retryPolicy.BaseDelay {
+			t.Fatalf("nextBackoff = %v, want >= BaseDelay %v", next, retryPolicy.BaseDelay)
+		}
+		previous = next
+	}
+}
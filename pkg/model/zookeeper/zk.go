@@ -18,6 +18,7 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"math/rand/v2"
 	"net"
@@ -29,9 +30,9 @@ import (
 	log "github.com/golang/glog"
 	"github.com/z-division/go-zookeeper/zk"
 	"golang.org/x/sync/semaphore"
-)
 
-const maxRetriesNum = 3
+	"github.com/altinity/clickhouse-operator/pkg/coordination"
+)
 
 var (
 	maxConcurrentRequests int64 = 32
@@ -43,24 +44,119 @@ var (
 	keyFile  string
 	caFile   string
 	authFile string
+
+	// serverNameOverride sets tls.Config.ServerName for every ensemble member,
+	// for deployments where all ZK nodes share one cert CN/SAN instead of
+	// each having its own.
+	serverNameOverride string
+
+	// retryPolicy and circuitBreakerPolicy are process-wide, same as the TLS
+	// settings above; SetRetryPolicy/SetCircuitBreakerPolicy let a CR's
+	// spec.zookeeper.retry and spec.zookeeper.circuitBreaker fields override
+	// the defaults at wire-up time.
+	retryPolicy          = DefaultRetryPolicy
+	circuitBreakerPolicy = DefaultCircuitBreakerPolicy
 )
 
+// RetryPolicy configures the exponential backoff with decorrelated jitter
+// used by Connection.retry between attempts against a single ensemble.
+type RetryPolicy struct {
+	// BaseDelay is the minimum sleep between attempts.
+	BaseDelay time.Duration
+	// MaxDelay caps how long any single sleep can grow to.
+	MaxDelay time.Duration
+	// Multiplier bounds how much the sleep window can grow attempt to
+	// attempt (decorrelated jitter: next = min(MaxDelay, random(BaseDelay, previous*Multiplier))).
+	Multiplier float64
+	// MaxRetries is the number of retries after the first attempt; 0 means
+	// the first attempt is never retried.
+	MaxRetries int
+}
+
+// DefaultRetryPolicy matches what operators have historically tuned ZK
+// ensembles for: fast enough to ride out a leader election, patient enough
+// not to hammer a struggling ensemble.
+var DefaultRetryPolicy = RetryPolicy{
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   30 * time.Second,
+	Multiplier: 3,
+	MaxRetries: 5,
+}
+
+// SetRetryPolicy overrides the backoff policy used by every Connection,
+// including ones already created, from their next operation on.
+func SetRetryPolicy(policy RetryPolicy) {
+	retryPolicy = policy
+}
+
+// CircuitBreakerPolicy bounds how long Connection.retry will keep dialing an
+// ensemble that has been failing, so a partitioned ZK cluster can't stall
+// reconciliation.
+type CircuitBreakerPolicy struct {
+	// FailureThreshold consecutive dial failures within Window before the
+	// breaker opens.
+	FailureThreshold int
+	// Window bounds how far apart failures can be and still count as
+	// "consecutive"; a failure older than Window resets the streak.
+	Window time.Duration
+	// CooldownPeriod is how long the breaker stays open (failing fast)
+	// before allowing a single probe attempt (half-open).
+	CooldownPeriod time.Duration
+}
+
+// DefaultCircuitBreakerPolicy opens after a handful of failures inside a
+// one-minute window and backs off for 30s before probing again.
+var DefaultCircuitBreakerPolicy = CircuitBreakerPolicy{
+	FailureThreshold: 5,
+	Window:           time.Minute,
+	CooldownPeriod:   30 * time.Second,
+}
+
+// SetCircuitBreakerPolicy overrides the circuit breaker policy used by
+// Connections created afterwards.
+func SetCircuitBreakerPolicy(policy CircuitBreakerPolicy) {
+	circuitBreakerPolicy = policy
+}
+
 type Connection struct {
 	address    string
 	sema       *semaphore.Weighted
 	mu         sync.Mutex
 	connection *zk.Conn
+	events     chan coordination.Event
+	breaker    *circuitBreaker
+	wg         sync.WaitGroup
+}
+
+// SetTLSConfig configures the TLS material used by all Connections created
+// afterwards. certFile/keyFile/caFile are paths to PEM files; serverName, if
+// non-empty, is used as tls.Config.ServerName for every ensemble member
+// instead of deriving it from each member's own address.
+func SetTLSConfig(cert, key, ca, serverName string) {
+	certFile = cert
+	keyFile = key
+	caFile = ca
+	serverNameOverride = serverName
 }
 
 func NewConnection(address string) *Connection {
 	return &Connection{
 		address: address,
 		sema:    semaphore.NewWeighted(maxConcurrentRequests),
+		events:  make(chan coordination.Event, 16),
+		breaker: newCircuitBreaker(address, circuitBreakerPolicy),
 	}
 }
 
+// Events returns a channel of session notifications for this Connection. It
+// satisfies the surface coordination.Client needs to expose watch/session
+// events in a backend-agnostic way.
+func (c *Connection) Events() <-chan coordination.Event {
+	return c.events
+}
+
 func (c *Connection) Get(ctx context.Context, path string) (data []byte, stat *zk.Stat, err error) {
-	err = c.retry(ctx, func(connection *zk.Conn) error {
+	err = c.retry(ctx, "get", func(connection *zk.Conn) error {
 		data, stat, err = connection.Get(path)
 		return err
 	})
@@ -68,7 +164,7 @@ func (c *Connection) Get(ctx context.Context, path string) (data []byte, stat *z
 }
 
 func (c *Connection) Exists(ctx context.Context, path string) (exists bool, stat *zk.Stat, err error) {
-	err = c.retry(ctx, func(connection *zk.Conn) error {
+	err = c.retry(ctx, "exists", func(connection *zk.Conn) error {
 		exists, stat, err = connection.Exists(path)
 		return err
 	})
@@ -76,7 +172,7 @@ func (c *Connection) Exists(ctx context.Context, path string) (exists bool, stat
 }
 
 func (c *Connection) Create(ctx context.Context, path string, value []byte, flags int32, acl []zk.ACL) (pathCreated string, err error) {
-	err = c.retry(ctx, func(connection *zk.Conn) error {
+	err = c.retry(ctx, "create", func(connection *zk.Conn) error {
 		pathCreated, err = connection.Create(path, value, flags, acl)
 		return err
 	})
@@ -84,7 +180,7 @@ func (c *Connection) Create(ctx context.Context, path string, value []byte, flag
 }
 
 func (c *Connection) Set(ctx context.Context, path string, value []byte, version int32) (stat *zk.Stat, err error) {
-	err = c.retry(ctx, func(connection *zk.Conn) error {
+	err = c.retry(ctx, "set", func(connection *zk.Conn) error {
 		stat, err = connection.Set(path, value, version)
 		return err
 	})
@@ -92,51 +188,167 @@ func (c *Connection) Set(ctx context.Context, path string, value []byte, version
 }
 
 func (c *Connection) Delete(ctx context.Context, path string, version int32) error {
-	return c.retry(ctx, func(connection *zk.Conn) error {
+	return c.retry(ctx, "delete", func(connection *zk.Conn) error {
 		return connection.Delete(path, version)
 	})
 }
 
+// Close closes the underlying ZK connection and, once connectionEventsProcessor
+// has observed that and returned, closes the channel returned by Events - the
+// same contract coordination.Client.Events documents. Callers must not invoke
+// Get/Exists/Create/Set/Delete concurrently with Close.
 func (c *Connection) Close() error {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-	if c.connection != nil {
-		c.connection.Close()
+	connection := c.connection
+	c.connection = nil
+	c.mu.Unlock()
+
+	if connection != nil {
+		connection.Close()
 	}
+	c.wg.Wait()
+	close(c.events)
 	return nil
 }
 
-func (c *Connection) retry(ctx context.Context, fn func(*zk.Conn) error) error {
+// retry runs fn against a live connection, retrying with exponential backoff
+// and decorrelated jitter on retryable failures. Context cancellation and
+// errors classified as terminal by isTerminalError short-circuit immediately.
+// A per-address circuit breaker fails fast, without dialing, once the
+// ensemble has been failing consistently.
+func (c *Connection) retry(ctx context.Context, op string, fn func(*zk.Conn) error) error {
 	if err := c.sema.Acquire(ctx, 1); err != nil {
 		return err
 	}
 	defer c.sema.Release(1)
 
-	for i := 0; i < maxRetriesNum; i++ {
-		if i > 0 {
-			time.Sleep(2*time.Second + time.Duration(rand.Int64N(5e9)))
+	var lastErr error
+	delay := retryPolicy.BaseDelay
+
+	for attempt := 0; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if !c.breaker.allow() {
+			recordOp(op, resultCircuitOpen)
+			return fmt.Errorf("zk conn: circuit breaker open for %s", c.address)
+		}
+
+		if attempt > 0 {
+			sleep := nextBackoff(&delay)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(sleep):
+			}
 		}
 
+		start := time.Now()
 		connection, err := c.ensureConnection(ctx)
 		if err != nil {
-			continue // Retry
+			if isCallerCanceled(ctx) {
+				recordOp(op, resultError)
+				return err
+			}
+			// dial bounds each attempt with its own timeoutConnect, so a
+			// context error here with ctx still live is the ensemble not
+			// answering in time: a dial failure, not a terminal error.
+			c.breaker.recordFailure()
+			lastErr = err
+			if attempt >= retryPolicy.MaxRetries {
+				break
+			}
+			continue
 		}
 
 		err = fn(connection)
+		observeOpDuration(op, time.Since(start))
+
 		if err == zk.ErrConnectionClosed {
 			c.mu.Lock()
 			if c.connection == connection {
 				c.connection = nil
 			}
 			c.mu.Unlock()
-			continue // Retry
+			c.breaker.recordFailure()
+			lastErr = err
+			if attempt >= retryPolicy.MaxRetries {
+				break
+			}
+			continue
 		}
 
-		// Got result
+		if err != nil && !isTerminalError(err) {
+			c.breaker.recordFailure()
+			lastErr = err
+			if attempt >= retryPolicy.MaxRetries {
+				break
+			}
+			continue
+		}
+
+		c.breaker.recordSuccess()
+		if err != nil {
+			recordOp(op, resultError)
+		} else {
+			recordOp(op, resultSuccess)
+		}
 		return err
 	}
 
-	return fmt.Errorf("max retries number reached")
+	recordOp(op, resultError)
+	return fmt.Errorf("zk conn: retries exhausted for op %s: %w", op, lastErr)
+}
+
+// nextBackoff implements decorrelated jitter (as popularized by AWS's
+// "Exponential Backoff And Jitter" post): the next sleep is drawn uniformly
+// from [BaseDelay, previous*Multiplier], capped at MaxDelay. *previous is
+// updated in place so the caller can feed it back in on the next attempt.
+func nextBackoff(previous *time.Duration) time.Duration {
+	base := retryPolicy.BaseDelay
+	maxDelay := retryPolicy.MaxDelay
+
+	upper := time.Duration(float64(*previous) * retryPolicy.Multiplier)
+	if upper < base {
+		upper = base
+	}
+
+	span := upper - base
+	next := base
+	if span > 0 {
+		next += time.Duration(rand.Int64N(int64(span) + 1))
+	}
+	if next > maxDelay {
+		next = maxDelay
+	}
+
+	*previous = next
+	return next
+}
+
+// isTerminalError reports whether err means retrying is pointless:
+// the caller's context ended, or ZK itself says the operation can never
+// succeed regardless of ensemble health (node already gone/already there).
+func isTerminalError(err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return true
+	case errors.Is(err, zk.ErrNoNode), errors.Is(err, zk.ErrNodeExists):
+		return true
+	default:
+		return false
+	}
+}
+
+// isCallerCanceled reports whether the caller gave up (its context was
+// canceled or timed out), as opposed to the ensemble actually being
+// unreachable. The circuit breaker tracks ensemble health, so dial errors
+// seen once the caller gave up don't count as failures against it.
+func isCallerCanceled(ctx context.Context) bool {
+	return ctx.Err() != nil
 }
 
 func (c *Connection) ensureConnection(ctx context.Context) (*zk.Conn, error) {
@@ -149,6 +361,7 @@ func (c *Connection) ensureConnection(ctx context.Context) (*zk.Conn, error) {
 			return nil, err
 		}
 		c.connection = connection
+		c.wg.Add(1)
 		go c.connectionEventsProcessor(connection, events)
 		c.connectionAddAuth(ctx)
 	}
@@ -178,6 +391,7 @@ func (c *Connection) connectionAddAuth(ctx context.Context) {
 }
 
 func (c *Connection) connectionEventsProcessor(connection *zk.Conn, events <-chan zk.Event) {
+	defer c.wg.Done()
 	for event := range events {
 		shouldCloseConnection := false
 		switch event.State {
@@ -196,9 +410,37 @@ func (c *Connection) connectionEventsProcessor(connection *zk.Conn, events <-cha
 				connection.Close()
 			}
 			log.Infof("zk conn: session for addr %v ended: %v", c.address, event)
+			c.emitEvent(coordination.Event{Type: coordination.EventSession, Path: event.Path})
 			return
 		}
 		log.Infof("zk conn: session for addr %v event: %v", c.address, event)
+		c.emitEvent(coordination.Event{Type: toCoordinationEventType(event.Type), Path: event.Path})
+	}
+}
+
+// emitEvent forwards to the events channel without blocking the zk event
+// loop if no one is reading.
+func (c *Connection) emitEvent(event coordination.Event) {
+	select {
+	case c.events <- event:
+	default:
+	}
+}
+
+func toCoordinationEventType(t zk.EventType) coordination.EventType {
+	switch t {
+	case zk.EventNodeCreated:
+		return coordination.EventNodeCreated
+	case zk.EventNodeDeleted:
+		return coordination.EventNodeDeleted
+	case zk.EventNodeDataChanged:
+		return coordination.EventNodeDataChanged
+	case zk.EventNodeChildrenChanged:
+		return coordination.EventNodeChildrenChanged
+	case zk.EventSession:
+		return coordination.EventSession
+	default:
+		return coordination.EventUnknown
 	}
 }
 
@@ -236,24 +478,55 @@ func (c *Connection) connect(address string) (*zk.Conn, <-chan zk.Event, error)
 	optionsDialer := zk.WithDialer(net.DialTimeout)
 
 	if certFile != "" && keyFile != "" {
-		if strings.Contains(address, ",") {
-			log.Fatalf("This TLS zk code requires that the all the zk servers validate to a single server name.")
+		dialer, err := newTLSDialer()
+		if err != nil {
+			return nil, nil, err
 		}
+		optionsDialer = zk.WithDialer(dialer)
+	}
 
-		serverName := strings.Split(address, ":")[0]
+	return zk.Connect(servers, timeoutKeepAlive, optionsDialer, optionsDNSHostProvider)
+}
 
-		log.Infof("Using TLS for %s/%s", address, serverName)
-		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
-		if err != nil {
-			log.Fatalf("Unable to load cert %v and key %v, err: %v", certFile, keyFile, err)
-		}
-		clientCACert, err := os.ReadFile(caFile)
-		if err != nil {
-			log.Fatalf("Unable to open ca cert %v, err %v", caFile, err)
-		}
+// tlsServerName picks the tls.Config.ServerName for one dial to address
+// (a single "host:port" ensemble member, not the full comma-separated
+// address). override, when non-empty, wins for every member - that's the
+// shared-cert-CN/SAN case; otherwise ServerName is derived from that
+// member's own host, so each node's cert is checked against its own SAN.
+func tlsServerName(address, override string) string {
+	if override != "" {
+		return override
+	}
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return host
+	}
+	return address
+}
+
+// newTLSDialer builds a zk.Dialer that TLS-dials each ensemble member
+// individually. tls.Config.ServerName is set per dial from the host part of
+// the address being connected to, so certificate verification matches that
+// server's own SAN entries - unlike a single shared tls.Config, this works
+// against a multi-server ensemble where each node presents a different
+// cert. When serverNameOverride is set, it is used for every dial instead,
+// for ensembles where all nodes share one cert CN/SAN.
+func newTLSDialer() (func(network, address string, timeout time.Duration) (net.Conn, error), error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load cert %v and key %v: %w", certFile, keyFile, err)
+	}
+	clientCACert, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open ca cert %v: %w", caFile, err)
+	}
+
+	clientCertPool := x509.NewCertPool()
+	clientCertPool.AppendCertsFromPEM(clientCACert)
 
-		clientCertPool := x509.NewCertPool()
-		clientCertPool.AppendCertsFromPEM(clientCACert)
+	return func(network, address string, timeout time.Duration) (net.Conn, error) {
+		serverName := tlsServerName(address, serverNameOverride)
+
+		log.Infof("Using TLS for %s/%s", address, serverName)
 
 		tlsConfig := &tls.Config{
 			Certificates: []tls.Certificate{cert},
@@ -261,14 +534,7 @@ func (c *Connection) connect(address string) (*zk.Conn, <-chan zk.Event, error)
 			ServerName:   serverName,
 		}
 
-		optionsDialer = zk.WithDialer(func(network, address string, timeout time.Duration) (net.Conn, error) {
-			d := net.Dialer{
-				Timeout: timeout,
-			}
-
-			return tls.DialWithDialer(&d, network, address, tlsConfig)
-		})
-	}
-
-	return zk.Connect(servers, timeoutKeepAlive, optionsDialer, optionsDNSHostProvider)
-}
\ No newline at end of file
+		d := net.Dialer{Timeout: timeout}
+		return tls.DialWithDialer(&d, network, address, tlsConfig)
+	}, nil
+}
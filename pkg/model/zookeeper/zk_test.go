@@ -0,0 +1,43 @@
+// Copyright 2019 Altinity Ltd and/or its affiliates. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zookeeper
+
+import "testing"
+
+// TestTLSServerName covers per-node SNI selection across a 3-node ensemble
+// with distinct SANs, plus the serverNameOverride path for ensembles that
+// share one cert CN/SAN.
+func TestTLSServerName(t *testing.T) {
+	tests := []struct {
+		name     string
+		address  string
+		override string
+		want     string
+	}{
+		{name: "node 1 of 3, no override", address: "zk-0.ensemble:2281", want: "zk-0.ensemble"},
+		{name: "node 2 of 3, no override", address: "zk-1.ensemble:2281", want: "zk-1.ensemble"},
+		{name: "node 3 of 3, no override", address: "zk-2.ensemble:2281", want: "zk-2.ensemble"},
+		{name: "override wins regardless of address", address: "zk-0.ensemble:2281", override: "zk.shared.cert", want: "zk.shared.cert"},
+		{name: "address without a port is used as-is", address: "zk-0.ensemble", want: "zk-0.ensemble"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tlsServerName(tt.address, tt.override); got != tt.want {
+				t.Errorf("tlsServerName(%q, %q) = %q, want %q", tt.address, tt.override, got, tt.want)
+			}
+		})
+	}
+}